@@ -0,0 +1,155 @@
+// Package chaos provides reusable fault-injection primitives for
+// acceptance tests that need to assert that Consul on Kubernetes recovers
+// from disruption: killed pods, rolled-out controllers, network
+// partitions, and paused containers.
+package chaos
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	terratestk8s "github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/hashicorp/consul-k8s/acceptance/framework/config"
+	"github.com/hashicorp/consul-k8s/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-k8s/acceptance/framework/logger"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// KillPod deletes podName with the given grace period. A zero grace period
+// force-deletes it, simulating a node failure or OOM kill rather than a
+// graceful termination.
+func KillPod(t *testing.T, client kubernetes.Interface, namespace, podName string, gracePeriod time.Duration) {
+	t.Helper()
+
+	logger.Logf(t, "force killing pod %q (grace period %s)", podName, gracePeriod)
+
+	seconds := int64(gracePeriod.Seconds())
+	err := client.CoreV1().Pods(namespace).Delete(context.Background(), podName, metav1.DeleteOptions{GracePeriodSeconds: &seconds})
+	require.NoError(t, err)
+}
+
+// LeaseHolderPod returns the name of the pod that currently holds the
+// named coordination/v1 Lease, which controller-runtime managers (such as
+// consul-k8s's endpoints-controller) use for leader election. The holder
+// identity is recorded as "<pod>_<uid>"; only the pod name portion is
+// returned.
+func LeaseHolderPod(t *testing.T, client kubernetes.Interface, namespace, leaseName string) string {
+	t.Helper()
+
+	lease, err := client.CoordinationV1().Leases(namespace).Get(context.Background(), leaseName, metav1.GetOptions{})
+	require.NoError(t, err)
+	require.NotNil(t, lease.Spec.HolderIdentity, "lease %q has no holder", leaseName)
+
+	holder := *lease.Spec.HolderIdentity
+	if idx := strings.LastIndex(holder, "_"); idx != -1 {
+		return holder[:idx]
+	}
+	return holder
+}
+
+// WorkloadKind identifies the kind of workload RolloutRestart should
+// restart.
+type WorkloadKind string
+
+const (
+	DaemonSet   WorkloadKind = "daemonset"
+	Deployment  WorkloadKind = "deployment"
+	StatefulSet WorkloadKind = "statefulset"
+)
+
+// RolloutRestart triggers a rollout restart of the named workload and
+// blocks until the rollout completes.
+func RolloutRestart(t *testing.T, cfg *config.TestConfig, options *terratestk8s.KubectlOptions, kind WorkloadKind, name string) {
+	t.Helper()
+
+	resource := fmt.Sprintf("%s/%s", kind, name)
+
+	logger.Logf(t, "restarting %s", resource)
+	k8s.RunKubectl(t, cfg, options, "rollout", "restart", resource)
+	k8s.RunKubectl(t, cfg, options, "rollout", "status", resource)
+}
+
+// PartitionNetwork denies egress from the pods matched by fromSelector to
+// the pods matched by toSelector for the given duration. Egress to the
+// other pods in the policy's namespace stays allowed; egress to pods in
+// other namespaces and to addresses that are not pods is denied as well
+// while the policy is in place. Both selectors are single "key=value"
+// labels. It does this by applying a
+// short-lived NetworkPolicy and removing it once the duration elapses (or
+// immediately, via the returned cleanup func, if the test wants to end the
+// partition early).
+func PartitionNetwork(t *testing.T, cfg *config.TestConfig, options *terratestk8s.KubectlOptions, fromSelector, toSelector string, duration time.Duration) (cleanup func()) {
+	t.Helper()
+
+	name := fmt.Sprintf("chaos-partition-%d", time.Now().UnixNano())
+
+	logger.Logf(t, "partitioning network: denying egress from %q to %q for %s", fromSelector, toSelector, duration)
+	k8s.ApplyYAML(t, cfg, options, networkPolicyYAML(name, fromSelector, toSelector))
+
+	timer := time.AfterFunc(duration, func() {
+		k8s.RunKubectl(t, cfg, options, "delete", "networkpolicy", name, "--ignore-not-found")
+	})
+
+	return func() {
+		timer.Stop()
+		k8s.RunKubectl(t, cfg, options, "delete", "networkpolicy", name, "--ignore-not-found")
+	}
+}
+
+// PauseContainer sends SIGSTOP to container in pod, freezing it in place,
+// and returns a resume func that sends SIGCONT.
+func PauseContainer(t *testing.T, cfg *config.TestConfig, options *terratestk8s.KubectlOptions, pod, container string) (resume func()) {
+	t.Helper()
+
+	logger.Logf(t, "pausing container %q in pod %q", container, pod)
+	k8s.RunKubectl(t, cfg, options, "exec", pod, "-c", container, "--", "kill", "-STOP", "1")
+
+	return func() {
+		logger.Logf(t, "resuming container %q in pod %q", container, pod)
+		k8s.RunKubectl(t, cfg, options, "exec", pod, "-c", container, "--", "kill", "-CONT", "1")
+	}
+}
+
+// networkPolicyYAML renders a NetworkPolicy that denies egress from
+// fromSelector to toSelector. NetworkPolicy has no "deny" rule, only
+// allow-lists, so this is expressed as a single egress rule that allows
+// traffic to every pod in the policy's namespace *except* toSelector, via
+// a matchExpressions NotIn on toSelector's label. The rule has no
+// namespaceSelector or ipBlock, so all other egress from fromSelector is
+// denied too.
+func networkPolicyYAML(name, fromSelector, toSelector string) string {
+	fromKey, fromValue := splitSelector(fromSelector)
+	key, value := splitSelector(toSelector)
+	return fmt.Sprintf(`apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: %s
+spec:
+  podSelector:
+    matchLabels:
+      %s: %s
+  policyTypes: ["Egress"]
+  egress:
+    - to:
+        - podSelector:
+            matchExpressions:
+              - key: %s
+                operator: NotIn
+                values: [%s]
+`, name, fromKey, fromValue, key, value)
+}
+
+// splitSelector splits a "key=value" label selector into its key and
+// value.
+func splitSelector(selector string) (key, value string) {
+	parts := strings.SplitN(selector, "=", 2)
+	if len(parts) != 2 {
+		return selector, ""
+	}
+	return parts[0], parts[1]
+}
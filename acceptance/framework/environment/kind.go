@@ -0,0 +1,118 @@
+package environment
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/consul-k8s/acceptance/framework/config"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/kind/pkg/cluster"
+)
+
+// kindClusterNames maps our context names to the kind cluster names it
+// creates, so that a multi-cluster suite run with -kind gets two
+// independent clusters rather than one cluster with two contexts.
+var kindClusterNames = map[string]string{
+	DefaultContextName:   "consul-k8s-acceptance",
+	SecondaryContextName: "consul-k8s-acceptance-secondary",
+}
+
+// KindEnvironment is a TestEnvironment backed by one or two ephemeral kind
+// clusters created at suite start, rather than a pre-existing cluster
+// reachable via -kubeconfig/-context.
+type KindEnvironment struct {
+	kenv           *KubernetesEnvironment
+	provider       *cluster.Provider
+	kubeconfigPath string
+	clusterNames   []string
+	cfg            *config.TestConfig
+}
+
+// NewKindEnvironment creates one kind cluster (or two, when
+// cfg.EnableMultiCluster is set), writes a merged kubeconfig for them, and
+// returns a KindEnvironment whose contexts point at those clusters.
+//
+// The caller is responsible for calling Destroy once the suite has
+// finished running, typically via TestMain.
+func NewKindEnvironment(t *testing.T, cfg *config.TestConfig) *KindEnvironment {
+	provider := cluster.NewProvider()
+
+	kubeconfigPath := filepath.Join(os.TempDir(), "consul-k8s-acceptance-kind-kubeconfig")
+
+	names := []string{kindClusterNames[DefaultContextName]}
+	if cfg.EnableMultiCluster {
+		names = append(names, kindClusterNames[SecondaryContextName])
+	}
+
+	for _, name := range names {
+		require.NoError(t, provider.Create(name))
+		require.NoError(t, provider.ExportKubeConfig(name, kubeconfigPath, false))
+	}
+
+	defaultContext := NewContext(cfg.KubeNamespace, kubeconfigPath, "kind-"+names[0])
+	defaultContext.noCleanupOnFailure = cfg.NoCleanupOnFailure
+	defaultContext.debugDirectory = cfg.DebugDirectory
+
+	kenv := &KubernetesEnvironment{
+		contexts: map[string]*kubernetesContext{
+			DefaultContextName: defaultContext,
+		},
+	}
+
+	if cfg.EnableMultiCluster {
+		secondaryContext := NewContext(cfg.SecondaryKubeNamespace, kubeconfigPath, "kind-"+names[1])
+		secondaryContext.noCleanupOnFailure = cfg.NoCleanupOnFailure
+		secondaryContext.debugDirectory = cfg.DebugDirectory
+		kenv.contexts[SecondaryContextName] = secondaryContext
+	}
+
+	return &KindEnvironment{
+		kenv:           kenv,
+		provider:       provider,
+		kubeconfigPath: kubeconfigPath,
+		clusterNames:   names,
+		cfg:            cfg,
+	}
+}
+
+func (k *KindEnvironment) DefaultContext(t *testing.T) TestContext {
+	return k.kenv.DefaultContext(t)
+}
+
+func (k *KindEnvironment) Context(t *testing.T, name string) TestContext {
+	return k.kenv.Context(t, name)
+}
+
+// LoadImage loads a locally-built image (e.g. consul-k8s-control-plane or
+// consul) into every cluster in the environment, so that pods can
+// reference it without the cluster needing registry access.
+func (k *KindEnvironment) LoadImage(t *testing.T, image string) {
+	t.Helper()
+
+	for _, name := range k.clusterNames {
+		cmd := exec.Command("kind", "load", "docker-image", image, "--name", name)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+}
+
+// Destroy tears down every kind cluster created by NewKindEnvironment,
+// unless cfg.NoCleanupOnFailure is set and the suite failed.
+func (k *KindEnvironment) Destroy(t *testing.T, suiteFailed bool) {
+	t.Helper()
+
+	if k.cfg.NoCleanupOnFailure && suiteFailed {
+		t.Logf("skipping kind cluster cleanup because -no-cleanup-on-failure is set")
+		return
+	}
+
+	for _, name := range k.clusterNames {
+		if err := k.provider.Delete(name, k.kubeconfigPath); err != nil {
+			t.Logf("deleting kind cluster %q: %s", name, err)
+		}
+	}
+
+	_ = os.Remove(k.kubeconfigPath)
+}
@@ -0,0 +1,13 @@
+// Package helpers holds generic helper functions shared across acceptance
+// test packages.
+package helpers
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// RandomName generates a random string with a release prefix.
+func RandomName() string {
+	return fmt.Sprintf("release-%d", rand.Int())
+}
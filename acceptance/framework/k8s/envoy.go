@@ -0,0 +1,221 @@
+package k8s
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	terratestk8s "github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/stretchr/testify/require"
+)
+
+// envoyAdminPort is the port the injected Envoy sidecar exposes its admin
+// interface on.
+const envoyAdminPort = 19000
+
+// envoyAdminEndpoints are the Envoy admin paths snapshotted into the debug
+// directory when an xDS assertion fails.
+var envoyAdminEndpoints = []string{"config_dump", "clusters", "certs"}
+
+// PortForwardToPod opens a port-forward from a random local port to
+// podPort on pod, returning the local address to dial and a func that
+// closes the tunnel. The tunnel is also closed in a t.Cleanup.
+func PortForwardToPod(t *testing.T, options *terratestk8s.KubectlOptions, pod string, podPort int) (localAddr string, closeTunnel func()) {
+	t.Helper()
+
+	tunnel := terratestk8s.NewTunnel(options, terratestk8s.ResourceTypePod, pod, 0, podPort)
+	tunnel.ForwardPort(t)
+
+	// The tunnel may be closed both by the caller and by t.Cleanup, so
+	// close it only once.
+	var closeOnce sync.Once
+	closeTunnel = func() { closeOnce.Do(tunnel.Close) }
+	t.Cleanup(closeTunnel)
+
+	return fmt.Sprintf("http://%s", tunnel.Endpoint()), closeTunnel
+}
+
+// EnvoyAdminGet issues a GET to path against pod's Envoy admin interface
+// (e.g. "clusters", "listeners", "stats?filter=ssl.handshake") and returns
+// the response body.
+func EnvoyAdminGet(t *testing.T, options *terratestk8s.KubectlOptions, pod, path string) (string, error) {
+	t.Helper()
+
+	addr, closeTunnel := PortForwardToPod(t, options, pod, envoyAdminPort)
+	defer closeTunnel()
+
+	resp, err := http.Get(fmt.Sprintf("%s/%s", addr, path))
+	if err != nil {
+		return "", fmt.Errorf("GET %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("reading %s response: %w", path, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GET %s: unexpected status %d: %s", path, resp.StatusCode, body)
+	}
+
+	return string(body), nil
+}
+
+// RequireEnvoyClusterForService asserts that pod's Envoy cluster config
+// contains an entry whose name references upstreamService and whose
+// certificate SANs include the SPIFFE URI spiffeURI. On failure it
+// snapshots pod's Envoy admin state into debugDirectory before failing.
+func RequireEnvoyClusterForService(t *testing.T, options *terratestk8s.KubectlOptions, pod, upstreamService, spiffeURI, debugDirectory string) {
+	t.Helper()
+
+	clusters, err := EnvoyAdminGet(t, options, pod, "clusters")
+	if err != nil {
+		SnapshotEnvoyAdmin(t, options, pod, debugDirectory)
+		require.NoError(t, err)
+	}
+
+	if !strings.Contains(clusters, upstreamService) || !strings.Contains(clusters, spiffeURI) {
+		SnapshotEnvoyAdmin(t, options, pod, debugDirectory)
+		require.Failf(t, "envoy cluster assertion failed",
+			"expected a cluster referencing %q and SPIFFE URI %q", upstreamService, spiffeURI)
+	}
+}
+
+// RequireEnvoyListenerRequiresClientCert asserts that pod's public listener
+// is configured with require_client_certificate: true, i.e. that mTLS is
+// actually being enforced rather than merely available. On failure it
+// snapshots pod's Envoy admin state into debugDirectory before failing.
+func RequireEnvoyListenerRequiresClientCert(t *testing.T, options *terratestk8s.KubectlOptions, pod, debugDirectory string) {
+	t.Helper()
+
+	dump, err := EnvoyAdminGet(t, options, pod, "config_dump?resource=dynamic_listeners")
+	if err != nil {
+		SnapshotEnvoyAdmin(t, options, pod, debugDirectory)
+		require.NoError(t, err)
+	}
+
+	var parsed interface{}
+	if err := json.Unmarshal([]byte(dump), &parsed); err != nil {
+		SnapshotEnvoyAdmin(t, options, pod, debugDirectory)
+		require.NoError(t, err, "parsing dynamic_listeners config_dump")
+	}
+
+	if !findBoolField(parsed, "require_client_certificate", true) {
+		SnapshotEnvoyAdmin(t, options, pod, debugDirectory)
+		require.Fail(t, "envoy listener assertion failed: expected require_client_certificate: true")
+	}
+}
+
+// findBoolField recursively searches a JSON value decoded by
+// encoding/json for a field named key whose value is want, returning true
+// as soon as one is found anywhere in the tree. Envoy nests
+// require_client_certificate several levels deep inside each filter
+// chain's transport socket config, at no fixed path, so a raw substring
+// match against the encoded JSON is unreliable: field order, casing, and
+// whitespace in the response are not guaranteed.
+func findBoolField(v interface{}, key string, want bool) bool {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if b, ok := val[key].(bool); ok && b == want {
+			return true
+		}
+		for _, child := range val {
+			if findBoolField(child, key, want) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, child := range val {
+			if findBoolField(child, key, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RequireEnvoyHandshakeOccurred asserts that at least one ssl.handshake
+// counter returned by pod's Envoy admin stats is non-zero, i.e. that an
+// mTLS handshake has actually taken place rather than merely that an HTTP
+// call returned 200. On failure it snapshots pod's Envoy admin state into
+// debugDirectory before failing.
+func RequireEnvoyHandshakeOccurred(t *testing.T, options *terratestk8s.KubectlOptions, pod, debugDirectory string) {
+	t.Helper()
+
+	stats, err := EnvoyAdminGet(t, options, pod, "stats?filter=ssl.handshake")
+	if err != nil {
+		SnapshotEnvoyAdmin(t, options, pod, debugDirectory)
+		require.NoError(t, err)
+	}
+
+	var found bool
+	var total int
+	for _, line := range strings.Split(strings.TrimSpace(stats), "\n") {
+		name, value, ok := parseEnvoyStatLine(line)
+		if !ok || !strings.Contains(name, "ssl.handshake") {
+			continue
+		}
+		found = true
+		total += value
+	}
+
+	if !found || total == 0 {
+		SnapshotEnvoyAdmin(t, options, pod, debugDirectory)
+		require.Failf(t, "envoy handshake assertion failed", "expected a non-zero ssl.handshake count across pod's listeners/clusters, got: %s", stats)
+	}
+}
+
+// parseEnvoyStatLine splits a line of Envoy's plaintext stats output
+// ("<counter name>: <value>") into its counter name and integer value.
+func parseEnvoyStatLine(line string) (name string, value int, ok bool) {
+	idx := strings.LastIndex(line, ":")
+	if idx < 0 {
+		return "", 0, false
+	}
+
+	value, err := strconv.Atoi(strings.TrimSpace(line[idx+1:]))
+	if err != nil {
+		return "", 0, false
+	}
+
+	return strings.TrimSpace(line[:idx]), value, true
+}
+
+// SnapshotEnvoyAdmin dumps pod's /config_dump, /clusters, and /certs into
+// debugDirectory for post-mortem inspection. debugDirectory being empty
+// (the common case when -no-cleanup-on-failure/-debug-directory are
+// unset) is a no-op. Failures fetching any individual endpoint are logged
+// but do not fail the test, since this already runs on another
+// assertion's failure path.
+func SnapshotEnvoyAdmin(t *testing.T, options *terratestk8s.KubectlOptions, pod, debugDirectory string) {
+	t.Helper()
+
+	if debugDirectory == "" {
+		return
+	}
+
+	dir := filepath.Join(debugDirectory, t.Name(), pod)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Logf("snapshotting envoy admin: creating %s: %s", dir, err)
+		return
+	}
+
+	for _, endpoint := range envoyAdminEndpoints {
+		body, err := EnvoyAdminGet(t, options, pod, endpoint)
+		if err != nil {
+			t.Logf("snapshotting envoy admin %s: %s", endpoint, err)
+			continue
+		}
+		path := filepath.Join(dir, endpoint+".json")
+		if err := ioutil.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Logf("snapshotting envoy admin %s: writing %s: %s", endpoint, path, err)
+		}
+	}
+}
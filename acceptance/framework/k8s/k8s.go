@@ -0,0 +1,148 @@
+// Package k8s holds helpers for interacting with a Kubernetes (or
+// OpenShift) cluster from acceptance tests.
+package k8s
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	terratestk8s "github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/hashicorp/consul-k8s/acceptance/framework/config"
+	"github.com/hashicorp/consul-k8s/acceptance/framework/logger"
+	"github.com/stretchr/testify/require"
+)
+
+// Binary returns the CLI binary that should be used to talk to the
+// cluster: "oc" when the config targets OpenShift, "kubectl" otherwise.
+func Binary(cfg *config.TestConfig) string {
+	if cfg != nil && cfg.EnableOpenshift {
+		return "oc"
+	}
+	return "kubectl"
+}
+
+// RunKubectl runs a kubectl (or oc, when cfg.EnableOpenshift is set)
+// command and fails the test if it returns an error.
+func RunKubectl(t *testing.T, cfg *config.TestConfig, options *terratestk8s.KubectlOptions, args ...string) {
+	t.Helper()
+
+	cmd := exec.Command(Binary(cfg), append(baseArgs(options), args...)...)
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+}
+
+// DeployKustomize deploys the kustomize directory at kustomizeDir, cleaning
+// it up at the end of the test unless noCleanupOnFailure is set and the
+// test failed.
+func DeployKustomize(t *testing.T, options *terratestk8s.KubectlOptions, noCleanupOnFailure bool, debugDirectory, kustomizeDir string) {
+	t.Helper()
+
+	out, err := terratestk8s.RunKubectlAndGetOutputE(t, options, "apply", "-k", kustomizeDir)
+	require.NoError(t, err, out)
+
+	t.Cleanup(func() {
+		if noCleanupOnFailure && t.Failed() {
+			logger.Logf(t, "skipping cleanup of %s because -no-cleanup-on-failure is set", kustomizeDir)
+			return
+		}
+		out, err := terratestk8s.RunKubectlAndGetOutputE(t, options, "delete", "-k", kustomizeDir)
+		require.NoError(t, err, out)
+	})
+}
+
+// CheckStaticServerConnectionSuccessful execs into the static-client pod
+// and curls url, asserting that the request succeeds.
+func CheckStaticServerConnectionSuccessful(t *testing.T, options *terratestk8s.KubectlOptions, url string) {
+	t.Helper()
+
+	out, err := terratestk8s.RunKubectlAndGetOutputE(t, options, "exec", "deploy/static-client", "-c", "static-client", "--", "curl", "-s", "-o", "/dev/null", "-w", "%{http_code}", url)
+	require.NoError(t, err, out)
+}
+
+// CheckStaticServerConnectionFails asserts that curling url from
+// static-client does not succeed, used to confirm that a fault (e.g. a
+// network partition) is actually blocking the connection.
+func CheckStaticServerConnectionFails(t *testing.T, options *terratestk8s.KubectlOptions, url string) {
+	t.Helper()
+
+	out, err := terratestk8s.RunKubectlAndGetOutputE(t, options, "exec", "deploy/static-client", "-c", "static-client", "--", "curl", "-s", "-o", "/dev/null", "-w", "%{http_code}", "--max-time", "5", url)
+	require.Error(t, err, out)
+}
+
+// BindSCCToServiceAccount grants the named OpenShift SecurityContextConstraints
+// to serviceAccount in options.Namespace, via `oc adm policy
+// add-scc-to-user`. SCC fixtures intentionally ship with no static
+// `users`/`groups` binding, since the namespace a test runs against is only
+// known at run time (it may be a generated ScopedNamespace), so callers
+// must bind the SCC themselves once that namespace exists.
+func BindSCCToServiceAccount(t *testing.T, cfg *config.TestConfig, options *terratestk8s.KubectlOptions, scc, serviceAccount string) {
+	t.Helper()
+
+	RunKubectl(t, cfg, options, "adm", "policy", "add-scc-to-user", scc, "-z", serviceAccount)
+}
+
+// ApplyYAML applies the given YAML manifest by piping it to kubectl/oc
+// apply -f -.
+func ApplyYAML(t *testing.T, cfg *config.TestConfig, options *terratestk8s.KubectlOptions, yaml string) {
+	t.Helper()
+
+	cmd := exec.Command(Binary(cfg), append(baseArgs(options), "apply", "-f", "-")...)
+	cmd.Stdin = strings.NewReader(yaml)
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+}
+
+// GetSecret returns the raw YAML of the named secret, so that it can be
+// copied into another cluster's context with ApplySecret.
+func GetSecret(t *testing.T, cfg *config.TestConfig, options *terratestk8s.KubectlOptions, name string) (string, error) {
+	t.Helper()
+
+	return terratestk8s.RunKubectlAndGetOutputE(t, options, "get", "secret", name, "-o", "yaml")
+}
+
+// ApplySecret applies the YAML produced by GetSecret into a different
+// cluster's context, stripping the fields that are specific to the
+// originating cluster (resourceVersion, uid, and similar metadata) so the
+// apply succeeds.
+func ApplySecret(t *testing.T, cfg *config.TestConfig, options *terratestk8s.KubectlOptions, secretYAML string) error {
+	t.Helper()
+
+	ApplyYAML(t, cfg, options, stripServerFields(secretYAML))
+	return nil
+}
+
+// stripServerFields removes metadata that the Kubernetes API server sets
+// and that would cause an apply into a different cluster to fail, such as
+// resourceVersion and uid.
+func stripServerFields(yaml string) string {
+	lines := strings.Split(yaml, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "resourceVersion:") ||
+			strings.HasPrefix(trimmed, "uid:") ||
+			strings.HasPrefix(trimmed, "selfLink:") ||
+			strings.HasPrefix(trimmed, "creationTimestamp:") {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n")
+}
+
+// baseArgs builds the --context/--namespace/--kubeconfig flags shared by
+// both kubectl and oc invocations.
+func baseArgs(options *terratestk8s.KubectlOptions) []string {
+	var args []string
+	if options.ContextName != "" {
+		args = append(args, "--context", options.ContextName)
+	}
+	if options.ConfigPath != "" {
+		args = append(args, "--kubeconfig", options.ConfigPath)
+	}
+	if options.Namespace != "" {
+		args = append(args, "--namespace", options.Namespace)
+	}
+	return args
+}
@@ -0,0 +1,17 @@
+// Package logger provides test-scoped logging helpers so that acceptance
+// tests have a single, consistent way of emitting progress output.
+package logger
+
+import "testing"
+
+// Log logs a message associated with the test t.
+func Log(t *testing.T, args ...interface{}) {
+	t.Helper()
+	t.Log(args...)
+}
+
+// Logf logs a formatted message associated with the test t.
+func Logf(t *testing.T, format string, args ...interface{}) {
+	t.Helper()
+	t.Logf(format, args...)
+}
@@ -0,0 +1,330 @@
+package connect
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/consul-k8s/acceptance/framework/config"
+	"github.com/hashicorp/consul-k8s/acceptance/framework/consul"
+	"github.com/hashicorp/consul-k8s/acceptance/framework/environment"
+	"github.com/hashicorp/consul-k8s/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-k8s/acceptance/framework/logger"
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// sharedClusters holds the Consul cluster installed for each
+// sharedClusterKey when ConnectHelper.SharedCluster is set, along with the
+// sync.Once that guards its single installation across parallel
+// sub-tests.
+var sharedClusters sync.Map // map[string]*sharedCluster
+
+type sharedCluster struct {
+	once    sync.Once
+	cluster consul.Cluster
+}
+
+// staticServerFixture and staticClientFixture are the Kustomize directories
+// deployed by Install/TestInstallation. On OpenShift they are swapped for
+// the "-openshift" variants, which run under their own ServiceAccount, with
+// no hostPath volumes and no fixed runAsUser, and add an SCC that
+// TestInstallation binds to that ServiceAccount.
+const (
+	staticServerFixture = "../fixtures/cases/static-server-inject"
+	staticClientFixture = "../fixtures/cases/static-client-inject"
+
+	staticServerOpenshiftFixture = "../fixtures/cases/static-server-inject-openshift"
+	staticClientOpenshiftFixture = "../fixtures/cases/static-client-inject-openshift"
+
+	// staticClientFederatedFixture is deployed instead of staticClientFixture
+	// by TestConnectInject_Federated: its static-client only exists in the
+	// primary datacenter, so its upstream annotation must be qualified with
+	// the secondary datacenter's name.
+	staticClientFederatedFixture = "../fixtures/cases/static-client-inject-federated"
+)
+
+// ConnectHelper is a helper for installing and testing a Consul cluster
+// with connect injection enabled.
+type ConnectHelper struct {
+	// ClusterGenerator generates the Consul cluster that is installed.
+	ClusterGenerator func(*testing.T, map[string]string, environment.TestContext, *config.TestConfig, string) consul.Cluster
+
+	Secure      bool
+	AutoEncrypt bool
+	ReleaseName string
+
+	// SharedCluster, when set, installs the release named ReleaseName at
+	// most once (guarded by a sync.Once keyed on sharedClusterKey, which
+	// also accounts for Secure/AutoEncrypt) and reuses it across every
+	// ConnectHelper that shares that key, so that sub-tests can run with
+	// t.Parallel() against workloads isolated by Ctx.ScopedNamespace
+	// instead of each getting a full cluster of their own.
+	SharedCluster bool
+
+	AdditionalHelmValues map[string]string
+
+	T   *testing.T
+	Ctx environment.TestContext
+	Cfg *config.TestConfig
+
+	// SecondaryCtx is the Kubernetes context for the secondary datacenter
+	// in a federated installation. It is only used by InstallSecondary and
+	// FederateWith.
+	SecondaryCtx environment.TestContext
+
+	Consul          consul.Cluster
+	ConsulClient    *api.Client
+	SecondaryConsul consul.Cluster
+}
+
+// helmValues returns the helm values for this installation, including the
+// OpenShift values required by c.Cfg.EnableOpenshift.
+func (c *ConnectHelper) helmValues() map[string]string {
+	values := map[string]string{
+		"connectInject.enabled":        "true",
+		"global.tls.enabled":           fmt.Sprintf("%t", c.Secure),
+		"global.tls.enableAutoEncrypt": fmt.Sprintf("%t", c.AutoEncrypt),
+		"global.acls.manageSystemACLs": fmt.Sprintf("%t", c.Secure),
+	}
+
+	for k, v := range c.AdditionalHelmValues {
+		values[k] = v
+	}
+
+	return values
+}
+
+// Install installs Consul according to the configuration of the
+// ConnectHelper. When SharedCluster is set, the cluster for this
+// sharedClusterKey is installed at most once; later calls sharing that key
+// reuse that installation instead of creating a new one.
+func (c *ConnectHelper) Install() error {
+	c.T.Helper()
+
+	if skip, reason := c.skipOnOpenshift(); skip {
+		c.T.Skip(reason)
+	}
+
+	if c.SharedCluster {
+		shared, _ := sharedClusters.LoadOrStore(c.sharedClusterKey(), &sharedCluster{})
+		sc := shared.(*sharedCluster)
+		sc.once.Do(func() {
+			sc.cluster = c.ClusterGenerator(c.T, c.helmValues(), c.Ctx, c.Cfg, c.ReleaseName)
+			sc.cluster.Create(c.T)
+		})
+		c.Consul = sc.cluster
+	} else {
+		c.Consul = c.ClusterGenerator(c.T, c.helmValues(), c.Ctx, c.Cfg, c.ReleaseName)
+		c.Consul.Create(c.T)
+	}
+
+	c.ConsulClient = c.Consul.SetupConsulClient(c.T, c.Secure)
+
+	return nil
+}
+
+// Upgrade upgrades the installed Consul cluster with the ConnectHelper's
+// current configuration.
+func (c *ConnectHelper) Upgrade() error {
+	c.T.Helper()
+
+	if skip, reason := c.skipOnOpenshift(); skip {
+		c.T.Skip(reason)
+	}
+
+	c.Consul.Upgrade(c.T, c.helmValues())
+	c.ConsulClient = c.Consul.SetupConsulClient(c.T, c.Secure)
+
+	return nil
+}
+
+// sharedClusterKey identifies the shared cluster this ConnectHelper should
+// install into or reuse. It includes Secure/AutoEncrypt alongside
+// ReleaseName so that cases sharing a ReleaseName (e.g. the CLI install
+// matrix, which always installs under consul.CLIReleaseName) but differing
+// in those settings don't collide on the same sync.Once and silently run
+// against whichever case's configuration won the race.
+func (c *ConnectHelper) sharedClusterKey() string {
+	return fmt.Sprintf("%s|secure=%t|autoEncrypt=%t", c.ReleaseName, c.Secure, c.AutoEncrypt)
+}
+
+// federationSecretName is the name of the Kubernetes secret that the
+// primary datacenter's Consul servers populate with the CA and replication
+// token needed for a secondary datacenter to federate over a mesh gateway.
+func (c *ConnectHelper) federationSecretName() string {
+	return fmt.Sprintf("%s-consul-federation", c.ReleaseName)
+}
+
+// federationHelmValues returns the Helm values needed to enable WAN
+// federation over mesh gateways, shared by the primary and secondary
+// installs.
+func (c *ConnectHelper) federationHelmValues(primary bool) map[string]string {
+	values := map[string]string{
+		"global.federation.enabled":                "true",
+		"global.federation.createFederationSecret": fmt.Sprintf("%t", primary),
+		"global.tls.enabled":                       "true",
+		"meshGateway.enabled":                      "true",
+		"meshGateway.replicas":                     "1",
+		"global.datacenter":                        "dc1",
+	}
+	if !primary {
+		values["global.datacenter"] = "dc2"
+		values["global.federation.primaryDatacenter"] = "dc1"
+		values["global.federation.k8sSecret"] = c.federationSecretName()
+	}
+	return values
+}
+
+// InstallSecondary installs a secondary Consul datacenter in c.SecondaryCtx,
+// configured to federate with the primary datacenter over a mesh gateway.
+// Install must be called first so that the primary's federation secret
+// exists.
+func (c *ConnectHelper) InstallSecondary() error {
+	c.T.Helper()
+
+	// Start from the same connect-inject and Secure/AutoEncrypt values as
+	// the primary, so that the secondary can run injected workloads too.
+	values := c.helmValues()
+	for k, v := range c.federationHelmValues(false) {
+		values[k] = v
+	}
+	for k, v := range c.AdditionalHelmValues {
+		values[k] = v
+	}
+
+	c.SecondaryConsul = c.ClusterGenerator(c.T, values, c.SecondaryCtx, c.Cfg, c.ReleaseName)
+	c.SecondaryConsul.Create(c.T)
+
+	return nil
+}
+
+// FederateWith copies the federation secret generated by the primary
+// datacenter's Consul servers into the secondary datacenter's context so
+// that its Consul servers can join the WAN over the mesh gateway. Install
+// must be called first so that c.Ctx has a federation secret to copy.
+func (c *ConnectHelper) FederateWith() error {
+	c.T.Helper()
+
+	logger.Logf(c.T, "copying federation secret %q to secondary context", c.federationSecretName())
+
+	secret, err := k8s.GetSecret(c.T, c.Cfg, c.Ctx.KubectlOptions(c.T), c.federationSecretName())
+	if err != nil {
+		return fmt.Errorf("reading federation secret: %w", err)
+	}
+
+	return k8s.ApplySecret(c.T, c.Cfg, c.SecondaryCtx.KubectlOptions(c.T), secret)
+}
+
+// TestInstallation deploys static-server and static-client and asserts that
+// they can connect to one another through the service mesh.
+func (c *ConnectHelper) TestInstallation() error {
+	c.T.Helper()
+
+	if skip, reason := c.skipOnOpenshift(); skip {
+		c.T.Skip(reason)
+	}
+
+	serverFixture, clientFixture := staticServerFixture, staticClientFixture
+	if c.Cfg.EnableOpenshift {
+		serverFixture, clientFixture = staticServerOpenshiftFixture, staticClientOpenshiftFixture
+	}
+
+	logger.Log(c.T, "deploying static-server and static-client")
+	k8s.DeployKustomize(c.T, c.Ctx.KubectlOptions(c.T), c.Cfg.NoCleanupOnFailure, c.Cfg.DebugDirectory, serverFixture)
+	k8s.DeployKustomize(c.T, c.Ctx.KubectlOptions(c.T), c.Cfg.NoCleanupOnFailure, c.Cfg.DebugDirectory, clientFixture)
+
+	if c.Cfg.EnableOpenshift {
+		// The server/client fixtures' scc.yaml just created the
+		// static-server-scc/static-client-scc objects above, so the bind
+		// can only happen now, not before the kustomize apply.
+		logger.Log(c.T, "binding OpenShift SCCs to the static-server and static-client service accounts")
+		k8s.BindSCCToServiceAccount(c.T, c.Cfg, c.Ctx.KubectlOptions(c.T), "static-server-scc", "static-server")
+		k8s.BindSCCToServiceAccount(c.T, c.Cfg, c.Ctx.KubectlOptions(c.T), "static-client-scc", "static-client")
+	}
+
+	logger.Log(c.T, "checking that connection is successful")
+	k8s.CheckStaticServerConnectionSuccessful(c.T, c.Ctx.KubectlOptions(c.T), "http://localhost:1234")
+
+	logger.Log(c.T, "asserting that mTLS is actually being enforced by the static-client sidecar")
+	c.requireEnvoyMTLSEnforced()
+
+	return nil
+}
+
+// requireEnvoyMTLSEnforced asserts, via static-client's Envoy admin
+// interface, that the connection check above succeeded because mTLS was
+// actually negotiated and enforced end-to-end, rather than merely because
+// an HTTP call happened to return 200: it asserts that static-client has a
+// cluster entry pointing at static-server's SPIFFE URI, that its listener
+// requires a client certificate, and that at least one SSL handshake has
+// occurred.
+func (c *ConnectHelper) requireEnvoyMTLSEnforced() {
+	c.T.Helper()
+
+	spiffeURI := fmt.Sprintf("spiffe://%s.consul/ns/default/dc/dc1/svc/static-server", c.trustDomain())
+
+	// The Envoy admin helpers port-forward to a pod by name, so look up the
+	// static-client pod rather than passing the deployment.
+	options := c.Ctx.KubectlOptions(c.T)
+	pods, err := c.Ctx.KubernetesClient(c.T).CoreV1().Pods(options.Namespace).List(context.Background(), metav1.ListOptions{LabelSelector: "app=static-client"})
+	require.NoError(c.T, err)
+	require.Len(c.T, pods.Items, 1)
+	pod := pods.Items[0].Name
+
+	k8s.RequireEnvoyClusterForService(c.T, options, pod, "static-server", spiffeURI, c.Cfg.DebugDirectory)
+	k8s.RequireEnvoyListenerRequiresClientCert(c.T, options, pod, c.Cfg.DebugDirectory)
+	k8s.RequireEnvoyHandshakeOccurred(c.T, options, pod, c.Cfg.DebugDirectory)
+}
+
+// trustDomain returns the Consul trust domain used to build the SPIFFE
+// URIs asserted against Envoy's cluster config. The trust domain is a UUID
+// generated when the CA is bootstrapped, so it must be read back from the
+// running cluster rather than assumed; Install must be called first so
+// that c.ConsulClient is set.
+func (c *ConnectHelper) trustDomain() string {
+	c.T.Helper()
+
+	self, err := c.ConsulClient.Agent().Self()
+	require.NoError(c.T, err)
+
+	cfg, ok := self["Config"].(map[string]interface{})
+	require.True(c.T, ok, "agent self response missing Config")
+
+	trustDomain, ok := cfg["TrustDomain"].(string)
+	require.True(c.T, ok, "agent self response missing Config.TrustDomain")
+
+	return trustDomain
+}
+
+// skipOnOpenshift reports whether this installation should be skipped on
+// OpenShift, and why. It skips transparent-proxy runs, whose privileged
+// init container the restricted SCC forbids, and CLI installs. Fixtures
+// with hostPath volumes or fixed UIDs are not checked here: TestInstallation
+// swaps in the "-openshift" fixture variants instead.
+func (c *ConnectHelper) skipOnOpenshift() (bool, string) {
+	if !c.Cfg.EnableOpenshift {
+		return false, ""
+	}
+
+	// The "-openshift" fixture variants only cover the plain
+	// static-server/static-client case: there is no
+	// static-client-tproxy-openshift fixture, since transparent proxy's
+	// CNI plugin requires a privileged init container that the
+	// restricted SCC forbids.
+	if c.Cfg.EnableTransparentProxy {
+		return true, "skipping on OpenShift: transparent proxy requires a privileged init container that the restricted SCC forbids"
+	}
+
+	// CLI installs (which always use consul.CLIReleaseName) are skipped on
+	// OpenShift, even though CLICluster receives global.openshift.enabled
+	// through mergeValues just like HelmCluster: only the Helm install path
+	// is run against OpenShift.
+	if c.ReleaseName == consul.CLIReleaseName {
+		return true, "skipping on OpenShift: CLI installs are not run against OpenShift"
+	}
+
+	return false, ""
+}
@@ -0,0 +1,151 @@
+package connect
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	terratestk8s "github.com/gruntwork-io/terratest/modules/k8s"
+	"github.com/hashicorp/consul-k8s/acceptance/framework/chaos"
+	"github.com/hashicorp/consul-k8s/acceptance/framework/consul"
+	"github.com/hashicorp/consul-k8s/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-k8s/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-k8s/acceptance/framework/logger"
+	"github.com/hashicorp/consul/sdk/testutil/retry"
+)
+
+// workloadFault names the Kubernetes workload a fault should target, given
+// the release name of the Consul installation under test. Exactly one of
+// (kind, nameOf) or leaseNameOf must be set: the former rolls out a
+// restart of the named workload, the latter force-kills whichever pod
+// currently holds the named leader-election Lease, without restarting the
+// rest of the deployment.
+type workloadFault struct {
+	kind   chaos.WorkloadKind
+	nameOf func(releaseName string) string
+
+	leaseNameOf func(releaseName string) string
+}
+
+// TestConnectInject_Chaos asserts that existing service registrations
+// recover, and static-client can still reach static-server, after each of
+// the workloads Consul on Kubernetes depends on is disrupted in turn. This
+// generalizes the fault coverage that TestConnectInject_RestartConsulClients
+// originally only exercised for the consul-client daemonset.
+func TestConnectInject_Chaos(t *testing.T) {
+	cases := map[string]workloadFault{
+		"consul-client daemonset": {
+			kind:   chaos.DaemonSet,
+			nameOf: func(releaseName string) string { return fmt.Sprintf("%s-consul-client", releaseName) },
+		},
+		"consul-server statefulset": {
+			kind:   chaos.StatefulSet,
+			nameOf: func(releaseName string) string { return fmt.Sprintf("%s-consul-server", releaseName) },
+		},
+		"connect-inject controller deployment": {
+			kind:   chaos.Deployment,
+			nameOf: func(releaseName string) string { return fmt.Sprintf("%s-consul-connect-injector", releaseName) },
+		},
+		"endpoints-controller leader": {
+			// The endpoints-controller runs inside the same
+			// consul-connect-injector deployment as the webhook server,
+			// so a rollout restart of that deployment would just repeat
+			// the "connect-inject controller deployment" case above.
+			// Force-killing the pod currently holding the leader-election
+			// Lease instead exercises the failover path specifically,
+			// without restarting the rest of the deployment.
+			leaseNameOf: func(releaseName string) string { return fmt.Sprintf("%s-consul-connect-injector", releaseName) },
+		},
+	}
+
+	for name, fault := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := suite.Config()
+			ctx := suite.Environment().DefaultContext(t)
+
+			helmValues := map[string]string{
+				"connectInject.enabled": "true",
+			}
+
+			releaseName := helpers.RandomName()
+			consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+			consulCluster.Create(t)
+
+			logger.Log(t, "creating static-server and static-client deployments")
+			k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, staticServerFixture)
+			k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, staticClientFixture)
+
+			logger.Log(t, "checking that connection is successful")
+			k8s.CheckStaticServerConnectionSuccessful(t, ctx.KubectlOptions(t), "http://localhost:1234")
+
+			if fault.leaseNameOf != nil {
+				leaseName := fault.leaseNameOf(releaseName)
+				client := ctx.KubernetesClient(t)
+				leader := chaos.LeaseHolderPod(t, client, ctx.KubectlOptions(t).Namespace, leaseName)
+
+				logger.Logf(t, "injecting fault: killing leader election lease %q holder pod %q", leaseName, leader)
+				chaos.KillPod(t, client, ctx.KubectlOptions(t).Namespace, leader, 0)
+			} else {
+				workloadName := fault.nameOf(releaseName)
+				logger.Logf(t, "injecting fault: rolling restart of %s %q", fault.kind, workloadName)
+				chaos.RolloutRestart(t, cfg, ctx.KubectlOptions(t), fault.kind, workloadName)
+			}
+
+			logger.Log(t, "waiting for the service registrations and the connection to recover")
+			consulClient := consulCluster.SetupConsulClient(t, false)
+			retry.RunWith(&retry.Timer{Timeout: 120 * time.Second, Wait: 2 * time.Second}, t, func(r *retry.R) {
+				for _, name := range []string{"static-server", "static-client"} {
+					instances, _, err := consulClient.Catalog().Service(name, "", nil)
+					r.Check(err)
+					if len(instances) != 1 {
+						r.Errorf("expected 1 instance of %s, got %d", name, len(instances))
+					}
+				}
+
+				out, err := terratestk8s.RunKubectlAndGetOutputE(t, ctx.KubectlOptions(t), "exec", "deploy/static-client", "-c", "static-client", "--", "curl", "-s", "-o", "/dev/null", "-w", "%{http_code}", "http://localhost:1234")
+				if err != nil {
+					r.Errorf("connection has not recovered: %s: %s", err, out)
+				}
+			})
+		})
+	}
+}
+
+// TestConnectInject_NetworkPartition asserts that static-client recovers
+// once a network partition isolating it from static-server is lifted.
+func TestConnectInject_NetworkPartition(t *testing.T) {
+	cfg := suite.Config()
+	ctx := suite.Environment().DefaultContext(t)
+
+	helmValues := map[string]string{
+		"connectInject.enabled": "true",
+	}
+
+	releaseName := helpers.RandomName()
+	consulCluster := consul.NewHelmCluster(t, helmValues, ctx, cfg, releaseName)
+	consulCluster.Create(t)
+
+	logger.Log(t, "creating static-server and static-client deployments")
+	k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, staticServerFixture)
+	k8s.DeployKustomize(t, ctx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, staticClientFixture)
+
+	logger.Log(t, "checking that connection is successful")
+	k8s.CheckStaticServerConnectionSuccessful(t, ctx.KubectlOptions(t), "http://localhost:1234")
+
+	logger.Log(t, "injecting fault: partitioning static-client off from static-server")
+	cleanup := chaos.PartitionNetwork(t, cfg, ctx.KubectlOptions(t), "app=static-client", "app=static-server", 30*time.Second)
+
+	logger.Log(t, "checking that the connection fails during the partition")
+	k8s.CheckStaticServerConnectionFails(t, ctx.KubectlOptions(t), "http://localhost:1234")
+
+	logger.Log(t, "lifting the partition")
+	cleanup()
+
+	logger.Log(t, "waiting for the connection to recover")
+	retry.RunWith(&retry.Timer{Timeout: 60 * time.Second, Wait: 2 * time.Second}, t, func(r *retry.R) {
+		out, err := terratestk8s.RunKubectlAndGetOutputE(t, ctx.KubectlOptions(t), "exec", "deploy/static-client", "-c", "static-client", "--", "curl", "-s", "-o", "/dev/null", "-w", "%{http_code}", "http://localhost:1234")
+		if err != nil {
+			r.Errorf("connection has not recovered: %s: %s", err, out)
+		}
+	})
+}
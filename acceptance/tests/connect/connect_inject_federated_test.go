@@ -0,0 +1,76 @@
+package connect
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/consul-k8s/acceptance/framework/consul"
+	"github.com/hashicorp/consul-k8s/acceptance/framework/environment"
+	"github.com/hashicorp/consul-k8s/acceptance/framework/helpers"
+	"github.com/hashicorp/consul-k8s/acceptance/framework/k8s"
+	"github.com/hashicorp/consul-k8s/acceptance/framework/logger"
+	"github.com/stretchr/testify/require"
+)
+
+// TestConnectInject_Federated installs two Consul datacenters, federated
+// over a mesh gateway, deploys static-server in the secondary datacenter
+// and static-client in the primary, and verifies that static-client can
+// reach static-server across the federated mesh.
+func TestConnectInject_Federated(t *testing.T) {
+	cases := []struct {
+		secure      bool
+		autoEncrypt bool
+	}{
+		{false, false},
+		{true, false},
+		{true, true},
+	}
+
+	for _, c := range cases {
+		name := fmt.Sprintf("secure: %t; auto-encrypt: %t", c.secure, c.autoEncrypt)
+		t.Run(name, func(t *testing.T) {
+			cfg := suite.Config()
+			if !cfg.EnableMultiCluster {
+				t.Skipf("skipping because -enable-multi-cluster is not set")
+			}
+
+			primaryCtx := suite.Environment().DefaultContext(t)
+			secondaryCtx := suite.Environment().Context(t, environment.SecondaryContextName)
+
+			connHelper := ConnectHelper{
+				ClusterGenerator: consul.NewHelmCluster,
+				Secure:           c.secure,
+				AutoEncrypt:      c.autoEncrypt,
+				ReleaseName:      helpers.RandomName(),
+				T:                t,
+				Ctx:              primaryCtx,
+				SecondaryCtx:     secondaryCtx,
+				Cfg:              cfg,
+			}
+			// The primary needs its own federation/mesh-gateway values so
+			// that it actually creates the federation secret FederateWith
+			// copies below; InstallSecondary applies its own secondary-side
+			// federationHelmValues and must not inherit these.
+			connHelper.AdditionalHelmValues = connHelper.federationHelmValues(true)
+
+			err := connHelper.Install()
+			require.NoError(t, err)
+			connHelper.AdditionalHelmValues = nil
+
+			err = connHelper.FederateWith()
+			require.NoError(t, err)
+
+			err = connHelper.InstallSecondary()
+			require.NoError(t, err)
+
+			logger.Log(t, "deploying static-server in the secondary datacenter")
+			k8s.DeployKustomize(t, secondaryCtx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, staticServerFixture)
+
+			logger.Log(t, "deploying static-client in the primary datacenter")
+			k8s.DeployKustomize(t, primaryCtx.KubectlOptions(t), cfg.NoCleanupOnFailure, cfg.DebugDirectory, staticClientFederatedFixture)
+
+			logger.Log(t, "checking that cross-cluster connection through the mesh gateway is successful")
+			k8s.CheckStaticServerConnectionSuccessful(t, primaryCtx.KubectlOptions(t), "http://localhost:1234")
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/hashicorp/consul-k8s/acceptance/framework/chaos"
 	"github.com/hashicorp/consul-k8s/acceptance/framework/config"
 	"github.com/hashicorp/consul-k8s/acceptance/framework/consul"
 	"github.com/hashicorp/consul-k8s/acceptance/framework/environment"
@@ -21,10 +22,11 @@ import (
 // TestConnectInject tests that Connect works in a default and a secure installation.
 func TestConnectInject(t *testing.T) {
 	cases := map[string]struct {
-		clusterGen  func(*testing.T, map[string]string, environment.TestContext, *config.TestConfig, string) consul.Cluster
-		releaseName string
-		secure      bool
-		autoEncrypt bool
+		clusterGen    func(*testing.T, map[string]string, environment.TestContext, *config.TestConfig, string) consul.Cluster
+		releaseName   string
+		secure        bool
+		autoEncrypt   bool
+		openshiftOnly bool
 	}{
 		"Helm install without secure or auto-encrypt": {
 			clusterGen:  consul.NewHelmCluster,
@@ -59,15 +61,33 @@ func TestConnectInject(t *testing.T) {
 	}
 
 	for name, c := range cases {
+		name, c := name, c
 		t.Run(name, func(t *testing.T) {
 			cfg := suite.Config()
+			if c.openshiftOnly && !cfg.EnableOpenshift {
+				t.Skipf("skipping because -enable-openshift is not set")
+			}
 			ctx := suite.Environment().DefaultContext(t)
 
+			// In -shared-cluster mode each case runs concurrently against
+			// its own namespace, and a release is installed at most once
+			// per sharedClusterKey (ReleaseName plus Secure/AutoEncrypt).
+			// The cases below all differ in that key, so each still gets
+			// its own release. t.Parallel() returns
+			// control to this for loop immediately, so name/c must be
+			// captured per-iteration above or every paused subtest would
+			// read whichever case the loop last landed on.
+			if cfg.UseSharedCluster {
+				t.Parallel()
+				ctx = ctx.ScopedNamespace(t)
+			}
+
 			connHelper := ConnectHelper{
 				ClusterGenerator: c.clusterGen,
 				Secure:           c.secure,
 				AutoEncrypt:      c.autoEncrypt,
 				ReleaseName:      c.releaseName,
+				SharedCluster:    cfg.UseSharedCluster,
 				T:                t,
 				Ctx:              ctx,
 				Cfg:              cfg,
@@ -190,10 +210,7 @@ func TestConnectInject_CleanupKilledPods(t *testing.T) {
 			require.Len(t, pods.Items, 1)
 			podName := pods.Items[0].Name
 
-			logger.Logf(t, "force killing the static-client pod %q", podName)
-			var gracePeriod int64 = 0
-			err = ctx.KubernetesClient(t).CoreV1().Pods(ns).Delete(context.Background(), podName, metav1.DeleteOptions{GracePeriodSeconds: &gracePeriod})
-			require.NoError(t, err)
+			chaos.KillPod(t, ctx.KubernetesClient(t), ns, podName, 0)
 
 			logger.Log(t, "ensuring pod is deregistered")
 			retry.Run(t, func(r *retry.R) {
@@ -242,9 +259,7 @@ func TestConnectInject_RestartConsulClients(t *testing.T) {
 		k8s.CheckStaticServerConnectionSuccessful(t, ctx.KubectlOptions(t), "http://localhost:1234")
 	}
 
-	logger.Log(t, "restarting Consul client daemonset")
-	k8s.RunKubectl(t, ctx.KubectlOptions(t), "rollout", "restart", fmt.Sprintf("ds/%s-consul-client", releaseName))
-	k8s.RunKubectl(t, ctx.KubectlOptions(t), "rollout", "status", fmt.Sprintf("ds/%s-consul-client", releaseName))
+	chaos.RolloutRestart(t, cfg, ctx.KubectlOptions(t), chaos.DaemonSet, fmt.Sprintf("%s-consul-client", releaseName))
 
 	logger.Log(t, "checking that connection is still successful")
 	if cfg.EnableTransparentProxy {